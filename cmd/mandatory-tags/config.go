@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ClusterConfig describes a single cluster (a Cloud Platform environment, or
+// any other org/cluster running the same Terraform layout) that the checker
+// can validate namespaces against.
+type ClusterConfig struct {
+	Name              string    `json:"name" yaml:"name"`
+	BasePath          string    `json:"basePath" yaml:"basePath"`
+	RequiredTags      []TagRule `json:"requiredTags" yaml:"requiredTags"`
+	IgnoreTagPatterns []string  `json:"ignoreTagPatterns" yaml:"ignoreTagPatterns"`
+}
+
+// TagRule describes a required tag and, optionally, the rules its value must
+// satisfy. A config file may write a tag as a bare string (equivalent to
+// {name: ..., required: true} with no value validation) or as the full
+// object below.
+type TagRule struct {
+	Name          string   `json:"name" yaml:"name"`
+	Pattern       string   `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+	AllowedValues []string `json:"allowedValues,omitempty" yaml:"allowedValues,omitempty"`
+	Required      bool     `json:"required" yaml:"required"`
+}
+
+// UnmarshalYAML lets a TagRule be written as either a bare tag name or a
+// full object with pattern/allowedValues/required fields.
+func (r *TagRule) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		var name string
+		if err := value.Decode(&name); err != nil {
+			return err
+		}
+		*r = TagRule{Name: name, Required: true}
+		return nil
+	}
+
+	type rawTagRule TagRule
+	var raw rawTagRule
+	if err := value.Decode(&raw); err != nil {
+		return err
+	}
+	*r = TagRule(raw)
+	return nil
+}
+
+// Config is the top-level shape of the tool's config file. It lets a single
+// invocation check every cluster a PR touches, rather than being hard-coded
+// to the justice.gov.uk live cluster.
+type Config struct {
+	Clusters []ClusterConfig `json:"clusters" yaml:"clusters"`
+}
+
+// defaultConfig mirrors the tool's historical single cluster and required
+// tag list, so running without a -config file checks the same cluster as
+// before; its tag rules also validate some values (see defaultTagRules).
+func defaultConfig() *Config {
+	return &Config{
+		Clusters: []ClusterConfig{
+			{
+				Name:         "live.cloud-platform",
+				BasePath:     basePath,
+				RequiredTags: defaultTagRules(),
+			},
+		},
+	}
+}
+
+// defaultTagRules mirrors the tool's historical required tag list, with
+// value-validation rules added for the tags Terraform conventionally
+// constrains to a small, checkable set of forms.
+func defaultTagRules() []TagRule {
+	rules := make([]TagRule, len(requiredTags))
+	for i, name := range requiredTags {
+		rules[i] = TagRule{Name: name, Required: true}
+	}
+
+	for i, rule := range rules {
+		switch rule.Name {
+		case "is-production":
+			rules[i].AllowedValues = []string{"true", "false"}
+		case "slack-channel":
+			rules[i].Pattern = `^#?[a-z0-9_-]+$`
+		case "source-code":
+			rules[i].Pattern = `^https://\S+$`
+		}
+	}
+
+	return rules
+}
+
+// loadConfig reads a YAML (or JSON, which is a subset of YAML) config file
+// describing the clusters to check.
+func loadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read config file: %w", err)
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("could not parse config file: %w", err)
+	}
+
+	if len(cfg.Clusters) == 0 {
+		return nil, fmt.Errorf("config file %s defines no clusters", path)
+	}
+
+	return &cfg, nil
+}
+
+// selectClusters narrows a config down to the cluster requested via -cluster,
+// applying a -base-path override if one was given. An empty name returns all
+// configured clusters unchanged.
+func selectClusters(cfg *Config, name, basePathOverride string) ([]ClusterConfig, error) {
+	clusters := cfg.Clusters
+	if name != "" {
+		clusters = nil
+		for _, c := range cfg.Clusters {
+			if c.Name == name {
+				clusters = append(clusters, c)
+			}
+		}
+		if len(clusters) == 0 {
+			return nil, fmt.Errorf("no cluster named %q in config", name)
+		}
+	}
+
+	if basePathOverride != "" {
+		if len(clusters) != 1 {
+			return nil, fmt.Errorf("-base-path requires exactly one cluster to be selected (use -cluster)")
+		}
+		clusters[0].BasePath = basePathOverride
+	}
+
+	return clusters, nil
+}
+
+// compileIgnorePatterns compiles a cluster's ignoreTagPatterns, following the
+// same `^aws:*`-style prefix convention used by the AWS provider's
+// ignore_tags setting.
+func compileIgnorePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ignoreTagPatterns entry %q: %w", p, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestTagRuleUnmarshalYAML(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want TagRule
+	}{
+		{
+			name: "bare string is a required tag with no value validation",
+			yaml: `owner`,
+			want: TagRule{Name: "owner", Required: true},
+		},
+		{
+			name: "full object form",
+			yaml: "name: is-production\nallowedValues: [\"true\", \"false\"]\nrequired: true",
+			want: TagRule{Name: "is-production", AllowedValues: []string{"true", "false"}, Required: true},
+		},
+		{
+			name: "full object form can opt out of required",
+			yaml: "name: cost-center\nrequired: false",
+			want: TagRule{Name: "cost-center", Required: false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got TagRule
+			if err := yaml.Unmarshal([]byte(tt.yaml), &got); err != nil {
+				t.Fatalf("could not parse tag rule: %v", err)
+			}
+			if got.Name != tt.want.Name || got.Required != tt.want.Required || len(got.AllowedValues) != len(tt.want.AllowedValues) {
+				t.Fatalf("parsed tag rule = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectClusters(t *testing.T) {
+	newConfig := func() *Config {
+		return &Config{Clusters: []ClusterConfig{
+			{Name: "live", BasePath: "namespaces/live"},
+			{Name: "test", BasePath: "namespaces/test"},
+		}}
+	}
+
+	t.Run("no name returns every cluster", func(t *testing.T) {
+		clusters, err := selectClusters(newConfig(), "", "")
+		if err != nil {
+			t.Fatalf("selectClusters returned an error: %v", err)
+		}
+		if len(clusters) != 2 {
+			t.Fatalf("got %d clusters, want 2", len(clusters))
+		}
+	})
+
+	t.Run("name narrows to one cluster", func(t *testing.T) {
+		clusters, err := selectClusters(newConfig(), "test", "")
+		if err != nil {
+			t.Fatalf("selectClusters returned an error: %v", err)
+		}
+		if len(clusters) != 1 || clusters[0].Name != "test" {
+			t.Fatalf("got %+v, want just the %q cluster", clusters, "test")
+		}
+	})
+
+	t.Run("unknown name is an error", func(t *testing.T) {
+		if _, err := selectClusters(newConfig(), "missing", ""); err == nil {
+			t.Fatalf("expected an error for an unknown cluster name")
+		}
+	})
+
+	t.Run("base path override requires exactly one cluster", func(t *testing.T) {
+		if _, err := selectClusters(newConfig(), "", "namespaces/override"); err == nil {
+			t.Fatalf("expected an error when -base-path is used without narrowing to one cluster")
+		}
+
+		clusters, err := selectClusters(newConfig(), "test", "namespaces/override")
+		if err != nil {
+			t.Fatalf("selectClusters returned an error: %v", err)
+		}
+		if clusters[0].BasePath != "namespaces/override" {
+			t.Fatalf("BasePath = %q, want override applied", clusters[0].BasePath)
+		}
+	})
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+)
+
+// terraformFile pairs a .tf file's content with the path used for HCL
+// diagnostics.
+type terraformFile struct {
+	path    string
+	content []byte
+}
+
+type providerInfo struct {
+	Name   string            `json:"name"`
+	Tags   []string          `json:"tags"`
+	Values map[string]string `json:"values,omitempty"`
+}
+
+// Violation is a single default_tags problem found in a provider block,
+// located precisely enough for CI tooling (e.g. SARIF) to annotate the
+// offending line.
+type Violation struct {
+	Provider string `json:"provider"`
+	Tag      string `json:"tag,omitempty"`
+	Value    string `json:"value,omitempty"`
+	Message  string `json:"message"`
+	Line     int    `json:"line"`
+}
+
+// listNamespaceTerraformFiles returns the content of every .tf file in a
+// namespace's resources directory on the given branch, so that locals and
+// variables referenced by default_tags can be resolved alongside main.tf.
+func listNamespaceTerraformFiles(vcs VCS, branch, dir string) ([]terraformFile, error) {
+	paths, err := vcs.ListDir(branch, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []terraformFile
+	for _, path := range paths {
+		if !strings.HasSuffix(path, ".tf") {
+			continue
+		}
+
+		content, err := vcs.FileAtRef(branch, path)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, terraformFile{path: path, content: content})
+	}
+
+	return files, nil
+}
+
+// buildEvalContext parses the locals and variable blocks out of a
+// namespace's .tf files, so default_tags expressions that reference
+// local.* or var.* can be evaluated. Attributes are resolved incrementally
+// over multiple passes, each pass evaluating every local/variable whose
+// dependencies are already in scope, so a local built with
+// merge(local.common_tags, {...}) or one that references another local
+// resolves correctly regardless of declaration order. An attribute that
+// still can't be evaluated once a pass makes no further progress (e.g. it
+// depends on a resource attribute or data source) is left out of scope and
+// reported via the returned diagnostics - it's the caller's job to decide
+// whether that failure actually matters for what it's using the context
+// for.
+func buildEvalContext(files []terraformFile) (*hcl.EvalContext, hcl.Diagnostics) {
+	parser := hclparse.NewParser()
+
+	type pendingAttr struct {
+		kind string // "local" or "var"
+		name string
+		expr hcl.Expression
+	}
+
+	var pending []pendingAttr
+	var diags hcl.Diagnostics
+
+	for _, tf := range files {
+		f, d := parser.ParseHCL(tf.content, tf.path)
+		diags = append(diags, d...)
+		if f == nil {
+			continue
+		}
+
+		body, ok := f.Body.(*hclsyntax.Body)
+		if !ok {
+			continue
+		}
+
+		for _, block := range body.Blocks {
+			switch block.Type {
+			case "locals":
+				for name, attr := range block.Body.Attributes {
+					pending = append(pending, pendingAttr{kind: "local", name: name, expr: attr.Expr})
+				}
+			case "variable":
+				if len(block.Labels) == 0 {
+					continue
+				}
+				if attr, ok := block.Body.Attributes["default"]; ok {
+					pending = append(pending, pendingAttr{kind: "var", name: block.Labels[0], expr: attr.Expr})
+				}
+			}
+		}
+	}
+
+	locals := map[string]cty.Value{}
+	variables := map[string]cty.Value{}
+	evalCtx := &hcl.EvalContext{
+		Variables: map[string]cty.Value{
+			"local": cty.ObjectVal(locals),
+			"var":   cty.ObjectVal(variables),
+		},
+		Functions: map[string]function.Function{
+			"merge": stdlib.MergeFunc,
+		},
+	}
+
+	// Keep resolving attributes against what's been resolved so far until a
+	// pass resolves nothing new - at that point whatever's left genuinely
+	// can't be evaluated from this namespace's locals/variables alone.
+	for len(pending) > 0 {
+		var unresolved []pendingAttr
+		var passDiags hcl.Diagnostics
+
+		for _, a := range pending {
+			val, d := a.expr.Value(evalCtx)
+			if d.HasErrors() {
+				unresolved = append(unresolved, a)
+				passDiags = append(passDiags, d...)
+				continue
+			}
+			if a.kind == "local" {
+				locals[a.name] = val
+			} else {
+				variables[a.name] = val
+			}
+		}
+
+		if len(unresolved) == len(pending) {
+			diags = append(diags, passDiags...)
+			break
+		}
+
+		evalCtx.Variables["local"] = cty.ObjectVal(locals)
+		evalCtx.Variables["var"] = cty.ObjectVal(variables)
+		pending = unresolved
+	}
+
+	return evalCtx, diags
+}
+
+// checkAllAwsProviders parses main.tf and verifies that every `provider
+// "aws"` block has a default_tags.tags map satisfying rules. The tags map is
+// evaluated against evalCtx so that values built from merge(), locals and
+// variables resolve the same way Terraform would see them. The returned
+// error is non-nil only for a catastrophic failure (the file doesn't parse,
+// or it has no AWS providers at all); rule violations are reported as
+// Violations instead, each carrying the line in main.tf it was found at.
+func checkAllAwsProviders(mainTf terraformFile, evalCtx *hcl.EvalContext, rules []TagRule, ignorePatterns []*regexp.Regexp) ([]providerInfo, []Violation, error) {
+	parser := hclparse.NewParser()
+	f, diags := parser.ParseHCL(mainTf.content, mainTf.path)
+	if diags.HasErrors() {
+		return nil, nil, fmt.Errorf("parsing %s: %s", mainTf.path, diags.Error())
+	}
+
+	body, ok := f.Body.(*hclsyntax.Body)
+	if !ok {
+		return nil, nil, fmt.Errorf("%s: unexpected HCL body", mainTf.path)
+	}
+
+	var providers []providerInfo
+	var violations []Violation
+	found := 0
+
+	for _, block := range body.Blocks {
+		if block.Type != "provider" || len(block.Labels) == 0 || block.Labels[0] != "aws" {
+			continue
+		}
+		found++
+
+		providerName := "aws (default)"
+		if aliasAttr, ok := block.Body.Attributes["alias"]; ok {
+			if v, d := aliasAttr.Expr.Value(evalCtx); !d.HasErrors() && v.Type() == cty.String {
+				providerName = fmt.Sprintf("aws (alias: %s)", v.AsString())
+			}
+		}
+
+		tagsAttr := findDefaultTagsAttribute(block.Body)
+		if tagsAttr == nil {
+			violations = append(violations, Violation{
+				Provider: providerName,
+				Message:  fmt.Sprintf("provider %q does not have a default_tags block with all required tags", providerName),
+				Line:     block.Range().Start.Line,
+			})
+			continue
+		}
+
+		tagsVal, d := tagsAttr.Expr.Value(evalCtx)
+		if d.HasErrors() {
+			violations = append(violations, Violation{
+				Provider: providerName,
+				Message:  fmt.Sprintf("provider %q has an unevaluatable default_tags.tags expression: %s", providerName, d.Error()),
+				Line:     tagsAttr.Expr.Range().Start.Line,
+			})
+			continue
+		}
+		if !tagsVal.CanIterateElements() {
+			violations = append(violations, Violation{
+				Provider: providerName,
+				Message:  fmt.Sprintf("provider %q default_tags.tags did not evaluate to a map", providerName),
+				Line:     tagsAttr.Expr.Range().Start.Line,
+			})
+			continue
+		}
+
+		values := map[string]string{}
+		var tags []string
+		it := tagsVal.ElementIterator()
+		for it.Next() {
+			k, v := it.Element()
+			name := k.AsString()
+			if matchesAny(ignorePatterns, name) {
+				continue
+			}
+
+			tags = append(tags, name)
+			if strVal, err := convert.Convert(v, cty.String); err == nil && !strVal.IsNull() {
+				values[name] = strVal.AsString()
+			}
+		}
+
+		providers = append(providers, providerInfo{Name: providerName, Tags: tags, Values: values})
+
+		for _, v := range validateTagRules(values, rules) {
+			v.Provider = providerName
+			v.Line = tagsAttr.Expr.Range().Start.Line
+			violations = append(violations, v)
+		}
+	}
+
+	if found == 0 {
+		return nil, nil, fmt.Errorf("no AWS providers found in %s", mainTf.path)
+	}
+
+	return providers, violations, nil
+}
+
+// findDefaultTagsAttribute digs into a provider block's nested default_tags
+// block and returns its tags attribute, or nil if either is absent.
+func findDefaultTagsAttribute(providerBody *hclsyntax.Body) *hclsyntax.Attribute {
+	for _, block := range providerBody.Blocks {
+		if block.Type != "default_tags" {
+			continue
+		}
+		if attr, ok := block.Body.Attributes["tags"]; ok {
+			return attr
+		}
+	}
+	return nil
+}
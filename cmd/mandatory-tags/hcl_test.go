@@ -0,0 +1,136 @@
+package main
+
+import "testing"
+
+func TestBuildEvalContextResolvesMergedLocals(t *testing.T) {
+	tests := []struct {
+		name      string
+		files     []terraformFile
+		wantTag   string
+		wantValue string
+	}{
+		{
+			name: "local built from merge() of another local",
+			files: []terraformFile{
+				{path: "locals.tf", content: []byte(`
+locals {
+  common_tags = {
+    business-unit = "example"
+  }
+  all_tags = merge(local.common_tags, {
+    owner = "platform-team"
+  })
+}
+`)},
+			},
+			wantTag:   "owner",
+			wantValue: "platform-team",
+		},
+		{
+			name: "local referencing a variable default",
+			files: []terraformFile{
+				{path: "variables.tf", content: []byte(`
+variable "environment" {
+  default = "production"
+}
+`)},
+				{path: "locals.tf", content: []byte(`
+locals {
+  all_tags = {
+    is-production = var.environment
+  }
+}
+`)},
+			},
+			wantTag:   "is-production",
+			wantValue: "production",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evalCtx, diags := buildEvalContext(tt.files)
+			if diags.HasErrors() {
+				t.Fatalf("buildEvalContext returned errors: %s", diags.Error())
+			}
+
+			allTags := evalCtx.Variables["local"].GetAttr("all_tags")
+			if !allTags.Type().IsObjectType() {
+				t.Fatalf("local.all_tags did not evaluate to an object: %s", allTags.Type().FriendlyName())
+			}
+
+			got := allTags.GetAttr(tt.wantTag)
+			if got.IsNull() || got.AsString() != tt.wantValue {
+				t.Fatalf("local.all_tags[%q] = %v, want %q", tt.wantTag, got, tt.wantValue)
+			}
+		})
+	}
+}
+
+func TestBuildEvalContextUnresolvableLocalDoesNotBlockOthers(t *testing.T) {
+	files := []terraformFile{
+		{path: "locals.tf", content: []byte(`
+locals {
+  broken    = aws_instance.example.id
+  all_tags  = { owner = "platform-team" }
+}
+`)},
+	}
+
+	evalCtx, diags := buildEvalContext(files)
+	if !diags.HasErrors() {
+		t.Fatalf("expected diagnostics for the unresolvable local, got none")
+	}
+
+	allTags := evalCtx.Variables["local"].GetAttr("all_tags")
+	if allTags.IsNull() {
+		t.Fatalf("local.all_tags should still resolve even though local.broken did not")
+	}
+	if got := allTags.GetAttr("owner"); got.IsNull() || got.AsString() != "platform-team" {
+		t.Fatalf("local.all_tags[owner] = %v, want %q", got, "platform-team")
+	}
+}
+
+func TestCheckAllAwsProvidersSucceedsWithMergedDefaultTags(t *testing.T) {
+	files := []terraformFile{
+		{path: "locals.tf", content: []byte(`
+locals {
+  common_tags = {
+    business-unit  = "example"
+    application    = "mandatory-tags"
+    owner          = "platform-team"
+    namespace      = "test-namespace"
+    service-area   = "platform"
+    source-code    = "https://github.com/example/example"
+    slack-channel  = "#platform"
+  }
+  all_tags = merge(local.common_tags, {
+    is-production = "true"
+  })
+}
+`)},
+	}
+	mainTf := terraformFile{path: "main.tf", content: []byte(`
+provider "aws" {
+  default_tags {
+    tags = local.all_tags
+  }
+}
+`)}
+
+	evalCtx, diags := buildEvalContext(files)
+	if diags.HasErrors() {
+		t.Fatalf("buildEvalContext returned errors: %s", diags.Error())
+	}
+
+	providers, violations, err := checkAllAwsProviders(mainTf, evalCtx, defaultTagRules(), nil)
+	if err != nil {
+		t.Fatalf("checkAllAwsProviders returned an error: %v", err)
+	}
+	if len(violations) != 0 {
+		t.Fatalf("unexpected violations: %+v", violations)
+	}
+	if len(providers) != 1 {
+		t.Fatalf("got %d providers, want 1", len(providers))
+	}
+}
@@ -1,18 +1,22 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
-	"os/exec"
-	"path/filepath"
 	"regexp"
 )
 
 var (
-	basePath   = "namespaces/live.cloud-platform.service.justice.gov.uk"
-	branchName = flag.String("branch", os.Getenv("BRANCH_NAME"), "The branch name to search")
-	help       = flag.Bool("h", false, "Show help message")
+	basePath     = "namespaces/live.cloud-platform.service.justice.gov.uk"
+	branchName   = flag.String("branch", os.Getenv("BRANCH_NAME"), "The branch name to search")
+	help         = flag.Bool("h", false, "Show help message")
+	configPath   = flag.String("config", os.Getenv("CONFIG_FILE"), "Path to a YAML config file declaring one or more clusters")
+	clusterName  = flag.String("cluster", os.Getenv("CLUSTER"), "Only check the named cluster from the config file")
+	basePathFlag = flag.String("base-path", "", "Override the base path of the selected cluster (requires exactly one cluster; use -cluster when several are configured)")
+	vcsKind      = flag.String("vcs", os.Getenv("VCS"), "VCS backend to use: git (default), github or gerrit")
+	format       = flag.String("format", envOr("FORMAT", "text"), "Output format: text, json or sarif")
 
 	requiredTags = []string{
 		"business-unit",
@@ -24,11 +28,16 @@ var (
 		"source-code",
 		"slack-channel",
 	}
-
-	awsProviderPattern = regexp.MustCompile(`(?s)provider\s+"aws"\s*\{(?:[^{}]|\{[^{}]*\})*\}`)
-	tagPattern         = regexp.MustCompile(`(?m)^\s*"?([a-zA-Z][a-zA-Z0-9_-]*)"?\s*=`)
 )
 
+// envOr returns the named environment variable, or fallback if it is unset.
+func envOr(name, fallback string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return fallback
+}
+
 func main() {
 	flag.Usage = printUsage
 	flag.Parse()
@@ -44,202 +53,170 @@ func main() {
 		os.Exit(1)
 	}
 
-	namespace, err := getNamespace(*branchName)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: Could not determine namespace: %v\n", err)
-		os.Exit(1)
+	cfg := defaultConfig()
+	if *configPath != "" {
+		loaded, err := loadConfig(*configPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		cfg = loaded
 	}
 
-	resourcePath := filepath.Join(basePath, namespace, "resources", "main.tf")
-
-	fmt.Printf("Searching for default_tags in %s on branch %s...\n\n", resourcePath, *branchName)
-
-	content, err := getFileFromBranch(*branchName, resourcePath)
+	clusters, err := selectClusters(cfg, *clusterName, *basePathFlag)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: Could not read file from branch: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	providers, err := checkAllAwsProviders(content)
+	vcs, err := newVCS(*vcsKind)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	fmt.Printf("✅ All %d AWS provider(s) have the required tags\n\n", len(providers))
-	for _, provider := range providers {
-		fmt.Printf("Provider: %s\n", provider.name)
-		fmt.Println("Tags:")
-		for _, tag := range provider.tags {
-			fmt.Printf("  ✓ %s\n", tag)
+	var jobs []scanJob
+	for _, cluster := range clusters {
+		namespaces, err := getNamespaces(vcs, *branchName, cluster.BasePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		// An empty list means this cluster's base path doesn't appear in the
+		// diff; the PR simply doesn't touch it.
+		for _, namespace := range namespaces {
+			jobs = append(jobs, scanJob{cluster: cluster, namespace: namespace})
 		}
-		fmt.Println()
 	}
-}
 
-// printUsage displays the help message for the command-line tool.
-func printUsage() {
-	fmt.Fprintf(os.Stderr, "Branch Default Tags Checker\n")
-	fmt.Fprintf(os.Stderr, "===========================\n\n")
-	fmt.Fprintf(os.Stderr, "This tool searches a git branch for default_tags in Terraform main.tf files.\n\n")
-	fmt.Fprintf(os.Stderr, "Usage: %s [options]\n\n", os.Args[0])
-	fmt.Fprintf(os.Stderr, "Options:\n")
-	flag.PrintDefaults()
-	fmt.Fprintf(os.Stderr, "\nEnvironment Variables:\n")
-	fmt.Fprintf(os.Stderr, "  NAMESPACE    - The namespace to search\n")
-	fmt.Fprintf(os.Stderr, "  BRANCH_NAME  - The branch name to search\n")
-	fmt.Fprintf(os.Stderr, "\nExamples:\n")
-	fmt.Fprintf(os.Stderr, "  %s -namespace=my-namespace -branch=my-branch\n", os.Args[0])
-	fmt.Fprintf(os.Stderr, "  NAMESPACE=my-namespace BRANCH_NAME=my-branch %s\n", os.Args[0])
-	fmt.Fprintf(os.Stderr, "  %s -h\n\n", os.Args[0])
-}
-
-// getFileFromBranch retrieves the content of a file from a specific git branch using git show.
-func getFileFromBranch(branch, filePath string) (string, error) {
-	cmd := exec.Command("git", "show", fmt.Sprintf("%s:%s", branch, filePath))
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return "", fmt.Errorf("git show failed: %w - %s", err, string(output))
+	if len(jobs) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: Could not determine namespace: branch %s does not touch any configured cluster\n", *branchName)
+		os.Exit(1)
 	}
-	return string(output), nil
-}
-
-type providerInfo struct {
-	name string
-	tags []string
-}
-
-// checkAllAwsProviders finds all AWS providers and verifies each has all required tags
-func checkAllAwsProviders(content string) ([]providerInfo, error) {
-	var providers []providerInfo
-	var errors []string
-
-	// Find all AWS provider blocks
-	lines := regexp.MustCompile(`provider\s+"aws"`).FindAllStringIndex(content, -1)
 
-	for _, loc := range lines {
-		// Extract the provider block starting from this location
-		start := loc[0]
-		providerBlock := extractProviderBlock(content[start:])
+	results := runScan(vcs, *branchName, jobs)
 
-		// Extract alias if present
-		aliasRegex := regexp.MustCompile(`alias\s*=\s*"([^"]+)"`)
-		aliasMatch := aliasRegex.FindStringSubmatch(providerBlock)
-
-		providerName := "aws (default)"
-		if len(aliasMatch) > 1 {
-			providerName = fmt.Sprintf("aws (alias: %s)", aliasMatch[1])
+	switch *format {
+	case "text", "":
+		printTextReport(results)
+	case "json":
+		if err := json.NewEncoder(os.Stdout).Encode(results); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: could not encode JSON report: %v\n", err)
+			os.Exit(1)
 		}
-
-		// Extract tags from default_tags block
-		tagsRegex := regexp.MustCompile(`(?s)default_tags\s*\{\s*tags\s*=\s*\{([^}]+)\}`)
-		tagsMatch := tagsRegex.FindStringSubmatch(providerBlock)
-
-		if len(tagsMatch) > 1 {
-			tags := extractTags(tagsMatch[1])
-			providers = append(providers, providerInfo{
-				name: providerName,
-				tags: tags,
-			})
-
-			missing := findMissingTags(tags)
-			if len(missing) > 0 {
-				errors = append(errors, fmt.Sprintf("❌ Provider '%s' is missing tags: %v", providerName, missing))
-			}
-		} else {
-			// Provider doesn't have default_tags at all
-			errors = append(errors, fmt.Sprintf("❌ Provider '%s' does not have default_tags block with all required tags", providerName))
+	case "sarif":
+		if err := json.NewEncoder(os.Stdout).Encode(buildSarifLog(results)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: could not encode SARIF report: %v\n", err)
+			os.Exit(1)
 		}
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown -format %q: must be text, json or sarif\n", *format)
+		os.Exit(1)
 	}
 
-	if len(lines) == 0 {
-		return nil, fmt.Errorf("❌ No AWS providers found in the file")
-	}
-
-	if len(errors) > 0 {
-		return nil, fmt.Errorf("%s", joinErrors(errors))
+	if reportHasFailures(results) {
+		os.Exit(1)
 	}
-
-	return providers, nil
 }
 
-// extractProviderBlock extracts a provider block by counting braces
-func extractProviderBlock(content string) string {
-	braceCount := 0
-	inBlock := false
+// printTextReport prints the scan results in the tool's original
+// human-readable style.
+func printTextReport(results []NamespaceResult) {
+	for _, r := range results {
+		fmt.Printf("[%s] Checked %s\n\n", r.Cluster, r.File)
 
-	for i, ch := range content {
-		if ch == '{' {
-			if !inBlock {
-				inBlock = true
-			}
-			braceCount++
-		} else if ch == '}' {
-			braceCount--
-			if braceCount == 0 && inBlock {
-				return content[:i+1]
+		if r.Error != "" {
+			fmt.Fprintf(os.Stderr, "[%s] Error: %s\n", r.Cluster, r.Error)
+			continue
+		}
+
+		if len(r.Violations) > 0 {
+			for _, v := range r.Violations {
+				fmt.Fprintf(os.Stderr, "[%s] ❌ %s:%d: %s\n", r.Cluster, r.File, v.Line, v.Message)
 			}
+			continue
 		}
-	}
-	return content
-} // findMissingTags returns tags that are required but not found in the provided list
-func findMissingTags(foundTags []string) []string {
-	tagMap := make(map[string]bool)
-	for _, tag := range foundTags {
-		tagMap[tag] = true
-	}
 
-	var missing []string
-	for _, required := range requiredTags {
-		if !tagMap[required] {
-			missing = append(missing, required)
+		fmt.Printf("[%s] ✅ All %d AWS provider(s) have the required tags\n\n", r.Cluster, len(r.Providers))
+		for _, provider := range r.Providers {
+			fmt.Printf("Provider: %s\n", provider.Name)
+			fmt.Println("Tags:")
+			for _, tag := range provider.Tags {
+				fmt.Printf("  ✓ %s\n", tag)
+			}
+			fmt.Println()
 		}
 	}
-	return missing
 }
 
-// joinErrors combines multiple error messages into one
-func joinErrors(errors []string) string {
-	result := ""
-	for i, err := range errors {
-		if i > 0 {
-			result += "\n"
+// reportHasFailures reports whether any namespace failed to check or had a
+// default_tags violation.
+func reportHasFailures(results []NamespaceResult) bool {
+	for _, r := range results {
+		if r.Error != "" || len(r.Violations) > 0 {
+			return true
 		}
-		result += err
 	}
-	return result
+	return false
 }
 
-// extractTags parses a tag block and extracts individual tag names using regex pattern matching.
-func extractTags(tagBlock string) []string {
-	var tags []string
+// printUsage displays the help message for the command-line tool.
+func printUsage() {
+	fmt.Fprintf(os.Stderr, "Branch Default Tags Checker\n")
+	fmt.Fprintf(os.Stderr, "===========================\n\n")
+	fmt.Fprintf(os.Stderr, "This tool searches a git branch for default_tags in Terraform main.tf files.\n\n")
+	fmt.Fprintf(os.Stderr, "Usage: %s [options]\n\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "Options:\n")
+	flag.PrintDefaults()
+	fmt.Fprintf(os.Stderr, "\nEnvironment Variables:\n")
+	fmt.Fprintf(os.Stderr, "  BRANCH_NAME  - The branch name to search\n")
+	fmt.Fprintf(os.Stderr, "  CONFIG_FILE  - Path to a YAML config file declaring one or more clusters\n")
+	fmt.Fprintf(os.Stderr, "  CLUSTER      - Only check the named cluster from the config file\n")
+	fmt.Fprintf(os.Stderr, "  VCS          - VCS backend to use: git (default), github or gerrit\n")
+	fmt.Fprintf(os.Stderr, "  FORMAT       - Output format: text (default), json or sarif\n")
+	fmt.Fprintf(os.Stderr, "  GITHUB_TOKEN, GITHUB_REPO, PR_NUMBER - required for -vcs=github\n")
+	fmt.Fprintf(os.Stderr, "  GERRIT_HOST, GERRIT_CHANGE_ID, GERRIT_REVISION_ID - required for -vcs=gerrit\n")
+	fmt.Fprintf(os.Stderr, "\nExamples:\n")
+	fmt.Fprintf(os.Stderr, "  %s -branch=my-branch\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  BRANCH_NAME=my-branch %s\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s -config=clusters.yaml -branch=my-branch\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s -config=clusters.yaml -cluster=test.cloud-platform -base-path=namespaces/test -branch=my-branch\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  GITHUB_TOKEN=... GITHUB_REPO=owner/repo PR_NUMBER=42 %s -vcs=github -branch=refs/pull/42/head\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s -h\n\n", os.Args[0])
+}
 
-	matches := tagPattern.FindAllStringSubmatch(tagBlock, -1)
-	for _, match := range matches {
-		if len(match) > 1 {
-			tags = append(tags, match[1])
+// findTerraformFile returns the file with the given path from files.
+func findTerraformFile(files []terraformFile, path string) (terraformFile, bool) {
+	for _, f := range files {
+		if f.path == path {
+			return f, true
 		}
 	}
-
-	return tags
+	return terraformFile{}, false
 }
 
-func getNamespace(branch string) (string, error) {
-	// get namespace from file path in the github pull request changed files `namespaces/live.cloud-platform.service.justice.gov.uk/<namespace>/...`
-
-	cmd := exec.Command("git", "diff", "main..."+branch, "--name-only")
-	output, err := cmd.CombinedOutput()
+// getNamespaces extracts every distinct namespace touched by the pull
+// request's changed files for a cluster, e.g.
+// `<clusterBasePath>/<namespace>/...`. It returns an empty list when the
+// changes don't touch the cluster, and an error only when the changed files
+// can't be listed.
+func getNamespaces(vcs VCS, branch, clusterBasePath string) ([]string, error) {
+	changed, err := vcs.ChangedFiles("main", branch)
 	if err != nil {
-		return "", fmt.Errorf("git diff failed: %w - %s", err, string(output))
+		return nil, err
 	}
 
-	changedFiles := string(output)
-
-	pattern := regexp.MustCompile(`namespaces/live\.cloud-platform\.service\.justice\.gov\.uk/([^/]+)/`)
-	matches := pattern.FindStringSubmatch(changedFiles)
-	if len(matches) > 1 {
-		return matches[1], nil
+	pattern := regexp.MustCompile(regexp.QuoteMeta(clusterBasePath) + `/([^/]+)/`)
+	seen := make(map[string]bool)
+	var namespaces []string
+	for _, file := range changed {
+		matches := pattern.FindStringSubmatch(file)
+		if len(matches) <= 1 || seen[matches[1]] {
+			continue
+		}
+		seen[matches[1]] = true
+		namespaces = append(namespaces, matches[1])
 	}
 
-	return "", fmt.Errorf("could not extract namespace from changed files in branch: %s", branch)
+	return namespaces, nil
 }
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// validateTagRules checks a provider's tag values against a cluster's tag
+// rules, returning one violation per failing rule. Required means the tag
+// must be present with a non-empty value - default_tags.tags = { owner = ""
+// } satisfies no reasonable tagging policy, so it's treated the same as the
+// tag being absent. The Provider and Line fields are left for the caller to
+// fill in, since this function only knows about tag values, not where in
+// main.tf they came from.
+func validateTagRules(values map[string]string, rules []TagRule) []Violation {
+	var violations []Violation
+
+	for _, rule := range rules {
+		value, present := values[rule.Name]
+		if !present || value == "" {
+			if rule.Required {
+				message := fmt.Sprintf("missing required tag %q", rule.Name)
+				if present {
+					message = fmt.Sprintf("required tag %q has an empty value", rule.Name)
+				}
+				violations = append(violations, Violation{
+					Tag:     rule.Name,
+					Message: message,
+				})
+			}
+			continue
+		}
+
+		if len(rule.AllowedValues) > 0 && !contains(rule.AllowedValues, value) {
+			violations = append(violations, Violation{
+				Tag:     rule.Name,
+				Value:   value,
+				Message: fmt.Sprintf("tag %q has value %q, must be one of %v", rule.Name, value, rule.AllowedValues),
+			})
+			continue
+		}
+
+		if rule.Pattern == "" {
+			continue
+		}
+
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			violations = append(violations, Violation{
+				Tag:     rule.Name,
+				Message: fmt.Sprintf("tag %q has an invalid validation pattern %q: %v", rule.Name, rule.Pattern, err),
+			})
+			continue
+		}
+		if !re.MatchString(value) {
+			violations = append(violations, Violation{
+				Tag:     rule.Name,
+				Value:   value,
+				Message: fmt.Sprintf("tag %q has value %q, which does not match pattern %q", rule.Name, value, rule.Pattern),
+			})
+		}
+	}
+
+	return violations
+}
+
+// matchesAny reports whether name matches any of patterns.
+func matchesAny(patterns []*regexp.Regexp, name string) bool {
+	for _, re := range patterns {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// contains reports whether list contains s.
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
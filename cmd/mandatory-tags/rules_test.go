@@ -0,0 +1,38 @@
+package main
+
+import "testing"
+
+func TestValidateTagRulesRejectsEmptyRequiredValue(t *testing.T) {
+	values := map[string]string{
+		"business-unit": "example",
+		"application":   "mandatory-tags",
+		"is-production": "true",
+		"owner":         "",
+		"namespace":     "test-namespace",
+		"service-area":  "platform",
+		"source-code":   "https://github.com/example/example",
+		"slack-channel": "#platform",
+	}
+
+	violations := validateTagRules(values, defaultTagRules())
+
+	var found bool
+	for _, v := range violations {
+		if v.Tag == "owner" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected a violation for the empty required tag %q, got %+v", "owner", violations)
+	}
+}
+
+func TestValidateTagRulesAllowsEmptyOptionalValue(t *testing.T) {
+	rules := []TagRule{{Name: "cost-center", Required: false}}
+	values := map[string]string{"cost-center": ""}
+
+	violations := validateTagRules(values, rules)
+	if len(violations) != 0 {
+		t.Fatalf("expected no violations for an empty optional tag, got %+v", violations)
+	}
+}
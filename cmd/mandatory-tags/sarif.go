@@ -0,0 +1,131 @@
+package main
+
+import "fmt"
+
+// missingTagsRuleID is the SARIF rule id reported for every default_tags
+// violation, so GitHub code-scanning can group them.
+const missingTagsRuleID = "missing-default-tags"
+
+// scanErrorRuleID is the SARIF rule id reported when a namespace couldn't be
+// checked at all (e.g. a parse failure or no AWS providers found), so those
+// failures still surface as inline PR annotations instead of only appearing
+// in the tool's own stderr.
+const scanErrorRuleID = "scan-error"
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// buildSarifLog converts scan results into a SARIF log with one result per
+// missing-tag violation, with physicalLocation pointing at the violating
+// line in main.tf, so GitHub code scanning can surface it inline on the PR
+// diff. A namespace that couldn't be checked at all gets a file-level
+// result too, so parse failures and similar scan errors aren't silently
+// dropped from the SARIF output.
+func buildSarifLog(results []NamespaceResult) sarifLog {
+	run := sarifRun{
+		Tool: sarifTool{
+			Driver: sarifDriver{
+				Name: "mandatory-tags",
+				Rules: []sarifRule{
+					{ID: missingTagsRuleID, Name: "MissingDefaultTags"},
+					{ID: scanErrorRuleID, Name: "ScanError"},
+				},
+			},
+		},
+	}
+
+	for _, r := range results {
+		if r.Error != "" {
+			run.Results = append(run.Results, sarifResult{
+				RuleID: scanErrorRuleID,
+				Level:  "error",
+				Message: sarifMessage{
+					Text: fmt.Sprintf("[%s/%s] %s", r.Cluster, r.Namespace, r.Error),
+				},
+				Locations: []sarifLocation{
+					{
+						PhysicalLocation: sarifPhysicalLocation{
+							ArtifactLocation: sarifArtifactLocation{URI: r.File},
+							Region:           sarifRegion{StartLine: 1},
+						},
+					},
+				},
+			})
+			continue
+		}
+
+		for _, v := range r.Violations {
+			run.Results = append(run.Results, sarifResult{
+				RuleID: missingTagsRuleID,
+				Level:  "error",
+				Message: sarifMessage{
+					Text: fmt.Sprintf("[%s/%s] %s", r.Cluster, r.Namespace, v.Message),
+				},
+				Locations: []sarifLocation{
+					{
+						PhysicalLocation: sarifPhysicalLocation{
+							ArtifactLocation: sarifArtifactLocation{URI: r.File},
+							Region:           sarifRegion{StartLine: v.Line},
+						},
+					},
+				},
+			})
+		}
+	}
+
+	return sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://raw.githubusercontent.com/oasis-tcs/sarif-spec/master/Schemata/sarif-schema-2.1.0.json",
+		Runs:    []sarifRun{run},
+	}
+}
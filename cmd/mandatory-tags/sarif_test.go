@@ -0,0 +1,54 @@
+package main
+
+import "testing"
+
+func TestBuildSarifLogEmitsViolationsAndScanErrors(t *testing.T) {
+	results := []NamespaceResult{
+		{
+			Cluster:   "live",
+			Namespace: "ok-namespace",
+			File:      "namespaces/live/ok-namespace/resources/main.tf",
+		},
+		{
+			Cluster:    "live",
+			Namespace:  "bad-namespace",
+			File:       "namespaces/live/bad-namespace/resources/main.tf",
+			Violations: []Violation{{Tag: "owner", Message: `missing required tag "owner"`, Line: 12}},
+		},
+		{
+			Cluster:   "live",
+			Namespace: "broken-namespace",
+			File:      "namespaces/live/broken-namespace/resources/main.tf",
+			Error:     "could not parse main.tf",
+		},
+	}
+
+	log := buildSarifLog(results)
+
+	if len(log.Runs) != 1 {
+		t.Fatalf("got %d runs, want 1", len(log.Runs))
+	}
+	run := log.Runs[0]
+	if len(run.Results) != 2 {
+		t.Fatalf("got %d results, want 2 (one violation, one scan error)", len(run.Results))
+	}
+
+	violation := run.Results[0]
+	if violation.RuleID != missingTagsRuleID {
+		t.Errorf("violation result RuleID = %q, want %q", violation.RuleID, missingTagsRuleID)
+	}
+	if violation.Locations[0].PhysicalLocation.Region.StartLine != 12 {
+		t.Errorf("violation result line = %d, want 12", violation.Locations[0].PhysicalLocation.Region.StartLine)
+	}
+
+	scanError := run.Results[1]
+	if scanError.RuleID != scanErrorRuleID {
+		t.Errorf("scan error result RuleID = %q, want %q", scanError.RuleID, scanErrorRuleID)
+	}
+	if scanError.Locations[0].PhysicalLocation.ArtifactLocation.URI != "namespaces/live/broken-namespace/resources/main.tf" {
+		t.Errorf("scan error result URI = %q, want the namespace's main.tf", scanError.Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	}
+	if scanError.Locations[0].PhysicalLocation.Region.StartLine != 1 {
+		t.Errorf("scan error result line = %d, want 1", scanError.Locations[0].PhysicalLocation.Region.StartLine)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+)
+
+// scanWorkers caps how many (cluster, namespace) pairs are checked
+// concurrently.
+const scanWorkers = 4
+
+// scanJob is one cluster/namespace pair to check.
+type scanJob struct {
+	cluster   ClusterConfig
+	namespace string
+}
+
+// NamespaceResult is the outcome of checking one namespace against one
+// cluster's rules.
+type NamespaceResult struct {
+	Cluster    string         `json:"cluster"`
+	Namespace  string         `json:"namespace"`
+	File       string         `json:"file"`
+	Providers  []providerInfo `json:"providers,omitempty"`
+	Violations []Violation    `json:"violations,omitempty"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// runScan checks every job with a fixed-size worker pool and returns one
+// result per job, in job order.
+func runScan(vcs VCS, branch string, jobs []scanJob) []NamespaceResult {
+	results := make([]NamespaceResult, len(jobs))
+
+	workers := scanWorkers
+	if workers > len(jobs) {
+		workers = len(jobs)
+	}
+
+	jobCh := make(chan int)
+	var wg sync.WaitGroup
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobCh {
+				results[i] = checkNamespace(vcs, branch, jobs[i].cluster, jobs[i].namespace)
+			}
+		}()
+	}
+
+	for i := range jobs {
+		jobCh <- i
+	}
+	close(jobCh)
+	wg.Wait()
+
+	return results
+}
+
+// checkNamespace runs the full default_tags check for one cluster/namespace
+// pair.
+func checkNamespace(vcs VCS, branch string, cluster ClusterConfig, namespace string) NamespaceResult {
+	resourceDir := filepath.Join(cluster.BasePath, namespace, "resources")
+	resourcePath := filepath.Join(resourceDir, "main.tf")
+
+	result := NamespaceResult{Cluster: cluster.Name, Namespace: namespace, File: resourcePath}
+
+	files, err := listNamespaceTerraformFiles(vcs, branch, resourceDir)
+	if err != nil {
+		result.Error = fmt.Sprintf("could not list namespace files from branch: %v", err)
+		return result
+	}
+
+	mainTf, ok := findTerraformFile(files, resourcePath)
+	if !ok {
+		result.Error = fmt.Sprintf("%s not found on branch %s", resourcePath, branch)
+		return result
+	}
+
+	// Diagnostics here mean some local/variable in the namespace couldn't be
+	// evaluated (e.g. it depends on a resource attribute or data source),
+	// not that default_tags itself is unreadable. Only the attributes that
+	// actually depend on the unresolved value are affected, and
+	// checkAllAwsProviders reports those as violations rather than aborting
+	// the whole namespace.
+	evalCtx, _ := buildEvalContext(files)
+
+	ignorePatterns, err := compileIgnorePatterns(cluster.IgnoreTagPatterns)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	providers, violations, err := checkAllAwsProviders(mainTf, evalCtx, cluster.RequiredTags, ignorePatterns)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Providers = providers
+	result.Violations = violations
+	return result
+}
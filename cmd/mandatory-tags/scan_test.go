@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// fakeVCS is an in-memory VCS backed by a fixed set of files, for tests that
+// don't need a real git/GitHub/Gerrit backend.
+type fakeVCS struct {
+	files map[string]string
+}
+
+func (f *fakeVCS) FileAtRef(_, path string) ([]byte, error) {
+	content, ok := f.files[path]
+	if !ok {
+		return nil, fmt.Errorf("%s not found", path)
+	}
+	return []byte(content), nil
+}
+
+func (f *fakeVCS) ChangedFiles(_, _ string) ([]string, error) {
+	var paths []string
+	for path := range f.files {
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+func (f *fakeVCS) ListDir(_, dir string) ([]string, error) {
+	prefix := strings.TrimRight(dir, "/") + "/"
+	var paths []string
+	for path := range f.files {
+		if strings.HasPrefix(path, prefix) {
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
+
+func TestCheckNamespaceSucceeds(t *testing.T) {
+	vcs := &fakeVCS{files: map[string]string{
+		"namespaces/live/test-namespace/resources/main.tf": `
+provider "aws" {
+  default_tags {
+    tags = local.all_tags
+  }
+}
+`,
+		"namespaces/live/test-namespace/resources/locals.tf": `
+locals {
+  all_tags = {
+    business-unit = "example"
+    application   = "mandatory-tags"
+    is-production = "true"
+    owner         = "platform-team"
+    namespace     = "test-namespace"
+    service-area  = "platform"
+    source-code   = "https://github.com/example/example"
+    slack-channel = "#platform"
+  }
+}
+`,
+	}}
+
+	cluster := ClusterConfig{Name: "live", BasePath: "namespaces/live", RequiredTags: defaultTagRules()}
+	result := checkNamespace(vcs, "main", cluster, "test-namespace")
+
+	if result.Error != "" {
+		t.Fatalf("unexpected error: %s", result.Error)
+	}
+	if len(result.Violations) != 0 {
+		t.Fatalf("unexpected violations: %+v", result.Violations)
+	}
+}
+
+func TestCheckNamespaceUnrelatedBrokenLocalDoesNotAbortTheNamespace(t *testing.T) {
+	vcs := &fakeVCS{files: map[string]string{
+		"namespaces/live/test-namespace/resources/main.tf": `
+provider "aws" {
+  default_tags {
+    tags = local.all_tags
+  }
+}
+`,
+		"namespaces/live/test-namespace/resources/locals.tf": `
+locals {
+  broken   = aws_instance.example.id
+  all_tags = {
+    business-unit = "example"
+    application   = "mandatory-tags"
+    is-production = "true"
+    owner         = "platform-team"
+    namespace     = "test-namespace"
+    service-area  = "platform"
+    source-code   = "https://github.com/example/example"
+    slack-channel = "#platform"
+  }
+}
+`,
+	}}
+
+	cluster := ClusterConfig{Name: "live", BasePath: "namespaces/live", RequiredTags: defaultTagRules()}
+	result := checkNamespace(vcs, "main", cluster, "test-namespace")
+
+	if result.Error != "" {
+		t.Fatalf("an unrelated unresolvable local should not fail the namespace, got error: %s", result.Error)
+	}
+	if len(result.Violations) != 0 {
+		t.Fatalf("unexpected violations: %+v", result.Violations)
+	}
+}
+
+func TestCheckNamespaceMissingMainTf(t *testing.T) {
+	vcs := &fakeVCS{files: map[string]string{}}
+	cluster := ClusterConfig{Name: "live", BasePath: "namespaces/live", RequiredTags: defaultTagRules()}
+
+	result := checkNamespace(vcs, "main", cluster, "missing-namespace")
+	if result.Error == "" {
+		t.Fatalf("expected an error for a namespace with no main.tf")
+	}
+}
+
+func TestRunScanPreservesJobOrder(t *testing.T) {
+	vcs := &fakeVCS{files: map[string]string{}}
+	cluster := ClusterConfig{Name: "live", BasePath: "namespaces/live"}
+
+	var jobs []scanJob
+	for i := 0; i < 10; i++ {
+		jobs = append(jobs, scanJob{cluster: cluster, namespace: fmt.Sprintf("namespace-%d", i)})
+	}
+
+	results := runScan(vcs, "main", jobs)
+	if len(results) != len(jobs) {
+		t.Fatalf("got %d results, want %d", len(results), len(jobs))
+	}
+	for i, r := range results {
+		if r.Namespace != fmt.Sprintf("namespace-%d", i) {
+			t.Fatalf("results[%d].Namespace = %q, want %q", i, r.Namespace, fmt.Sprintf("namespace-%d", i))
+		}
+	}
+}
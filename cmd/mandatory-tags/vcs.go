@@ -0,0 +1,355 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// VCS abstracts the version-control operations the checker needs, so it can
+// run somewhere without a working tree - e.g. a webhook receiver that only
+// has a repo URL and PR number.
+type VCS interface {
+	// FileAtRef returns the content of path as it exists at ref.
+	FileAtRef(ref, path string) ([]byte, error)
+	// ChangedFiles returns the paths changed between base and head.
+	ChangedFiles(base, head string) ([]string, error)
+	// ListDir returns the paths of the files directly inside dir at ref.
+	ListDir(ref, dir string) ([]string, error)
+}
+
+// newVCS constructs the VCS backend named by kind ("git", "github" or
+// "gerrit"). An empty kind defaults to git, the tool's original backend.
+func newVCS(kind string) (VCS, error) {
+	switch kind {
+	case "", "git":
+		return gitVCS{}, nil
+	case "github":
+		return newGitHubVCS()
+	case "gerrit":
+		return newGerritVCS()
+	default:
+		return nil, fmt.Errorf("unknown -vcs %q: must be git, github or gerrit", kind)
+	}
+}
+
+// gitVCS implements VCS against a local git working tree, using `git show`
+// and `git diff` as the tool originally did, plus `git ls-tree` for ListDir.
+type gitVCS struct{}
+
+func (gitVCS) FileAtRef(ref, path string) ([]byte, error) {
+	cmd := exec.Command("git", "show", fmt.Sprintf("%s:%s", ref, path))
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("git show failed: %w - %s", err, string(output))
+	}
+	return output, nil
+}
+
+func (gitVCS) ChangedFiles(base, head string) ([]string, error) {
+	cmd := exec.Command("git", "diff", base+"..."+head, "--name-only")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("git diff failed: %w - %s", err, string(output))
+	}
+	return splitLines(string(output)), nil
+}
+
+func (gitVCS) ListDir(ref, dir string) ([]string, error) {
+	cmd := exec.Command("git", "ls-tree", ref, dir+"/")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("git ls-tree failed: %w - %s", err, string(output))
+	}
+
+	// Each line is "<mode> <type> <object>\t<path>"; keep only blobs so
+	// subdirectories are left out.
+	var paths []string
+	for _, line := range splitLines(string(output)) {
+		meta, path, ok := strings.Cut(line, "\t")
+		if fields := strings.Fields(meta); ok && len(fields) == 3 && fields[1] == "blob" {
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
+
+// splitLines splits command output into non-empty, trimmed lines.
+func splitLines(s string) []string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// githubVCS implements VCS against the GitHub REST API, so the checker can
+// run as a GitHub Action step without cloning the repository.
+type githubVCS struct {
+	token    string
+	repo     string // "owner/name"
+	prNumber int
+	client   *http.Client
+
+	// baseURL overrides the GitHub API's base URL. Empty means the real
+	// API; tests set it to a fake server's URL.
+	baseURL string
+}
+
+func newGitHubVCS() (*githubVCS, error) {
+	token := os.Getenv("GITHUB_TOKEN")
+	repo := os.Getenv("GITHUB_REPO")
+	prNumberStr := os.Getenv("PR_NUMBER")
+
+	if token == "" || repo == "" || prNumberStr == "" {
+		return nil, fmt.Errorf("-vcs=github requires GITHUB_TOKEN, GITHUB_REPO and PR_NUMBER to be set")
+	}
+
+	prNumber, err := strconv.Atoi(prNumberStr)
+	if err != nil {
+		return nil, fmt.Errorf("PR_NUMBER must be an integer: %w", err)
+	}
+
+	return &githubVCS{token: token, repo: repo, prNumber: prNumber, client: http.DefaultClient}, nil
+}
+
+func (g *githubVCS) do(path string, query url.Values) ([]byte, error) {
+	base := g.baseURL
+	if base == "" {
+		base = "https://api.github.com"
+	}
+	u := fmt.Sprintf("%s/repos/%s/%s", strings.TrimRight(base, "/"), g.repo, path)
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+g.token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("github api request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("github api returned %s: %s", resp.Status, string(body))
+	}
+
+	return body, nil
+}
+
+// FileAtRef fetches path's content at ref (typically the PR head SHA) via
+// the GitHub contents API.
+func (g *githubVCS) FileAtRef(ref, path string) ([]byte, error) {
+	body, err := g.do("contents/"+path, url.Values{"ref": {ref}})
+	if err != nil {
+		return nil, err
+	}
+
+	var entry struct {
+		Content  string `json:"content"`
+		Encoding string `json:"encoding"`
+	}
+	if err := json.Unmarshal(body, &entry); err != nil {
+		return nil, fmt.Errorf("could not parse github contents response: %w", err)
+	}
+	if entry.Encoding != "base64" {
+		return nil, fmt.Errorf("unexpected github content encoding %q", entry.Encoding)
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(entry.Content, "\n", ""))
+	if err != nil {
+		return nil, fmt.Errorf("could not decode github file content: %w", err)
+	}
+
+	return decoded, nil
+}
+
+// ChangedFiles lists the files changed by the configured pull request. base
+// and head are ignored in favour of PR_NUMBER, since the GitHub API already
+// knows the PR's diff.
+func (g *githubVCS) ChangedFiles(_, _ string) ([]string, error) {
+	var files []string
+	for page := 1; ; page++ {
+		body, err := g.do(fmt.Sprintf("pulls/%d/files", g.prNumber), url.Values{
+			"per_page": {"100"},
+			"page":     {strconv.Itoa(page)},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		var entries []struct {
+			Filename string `json:"filename"`
+		}
+		if err := json.Unmarshal(body, &entries); err != nil {
+			return nil, fmt.Errorf("could not parse github pull files response: %w", err)
+		}
+		if len(entries) == 0 {
+			break
+		}
+
+		for _, e := range entries {
+			files = append(files, e.Filename)
+		}
+	}
+
+	return files, nil
+}
+
+// ListDir lists the files directly inside dir at ref via the GitHub contents
+// API.
+func (g *githubVCS) ListDir(ref, dir string) ([]string, error) {
+	body, err := g.do("contents/"+dir, url.Values{"ref": {ref}})
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []struct {
+		Path string `json:"path"`
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(body, &entries); err != nil {
+		return nil, fmt.Errorf("could not parse github contents response: %w", err)
+	}
+
+	var paths []string
+	for _, e := range entries {
+		if e.Type == "file" {
+			paths = append(paths, e.Path)
+		}
+	}
+	return paths, nil
+}
+
+// gerritVCS implements VCS against a Gerrit REST API. Gerrit identifies
+// content by change+revision rather than by a git ref, so ref is ignored in
+// favour of the configured revision.
+type gerritVCS struct {
+	host     string
+	change   string
+	revision string
+	client   *http.Client
+}
+
+func newGerritVCS() (*gerritVCS, error) {
+	host := os.Getenv("GERRIT_HOST")
+	change := os.Getenv("GERRIT_CHANGE_ID")
+	revision := os.Getenv("GERRIT_REVISION_ID")
+
+	if host == "" || change == "" {
+		return nil, fmt.Errorf("-vcs=gerrit requires GERRIT_HOST and GERRIT_CHANGE_ID to be set")
+	}
+	if revision == "" {
+		revision = "current"
+	}
+
+	return &gerritVCS{host: host, change: change, revision: revision, client: http.DefaultClient}, nil
+}
+
+// gerritMagicPrefix is prepended to every Gerrit REST response body to guard
+// against XSSI and must be stripped before JSON-decoding it.
+const gerritMagicPrefix = ")]}'\n"
+
+func (g *gerritVCS) get(path string) ([]byte, error) {
+	u := fmt.Sprintf("%s/a/changes/%s/revisions/%s/%s", strings.TrimRight(g.host, "/"), url.PathEscape(g.change), url.PathEscape(g.revision), path)
+
+	resp, err := g.client.Get(u)
+	if err != nil {
+		return nil, fmt.Errorf("gerrit api request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("gerrit api returned %s: %s", resp.Status, string(body))
+	}
+
+	return bytes.TrimPrefix(body, []byte(gerritMagicPrefix)), nil
+}
+
+// FileAtRef ignores ref in favour of the configured change/revision.
+func (g *gerritVCS) FileAtRef(_, path string) ([]byte, error) {
+	body, err := g.get("files/" + url.PathEscape(path) + "/content")
+	if err != nil {
+		return nil, err
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(body)))
+	if err != nil {
+		return nil, fmt.Errorf("could not decode gerrit file content: %w", err)
+	}
+	return decoded, nil
+}
+
+// ChangedFiles ignores base/head in favour of the configured change and
+// revision.
+func (g *gerritVCS) ChangedFiles(_, _ string) ([]string, error) {
+	return g.listFiles("")
+}
+
+func (g *gerritVCS) listFiles(prefix string) ([]string, error) {
+	body, err := g.get("files")
+	if err != nil {
+		return nil, err
+	}
+
+	var files map[string]json.RawMessage
+	if err := json.Unmarshal(body, &files); err != nil {
+		return nil, fmt.Errorf("could not parse gerrit files response: %w", err)
+	}
+
+	var paths []string
+	for path := range files {
+		if path == "/COMMIT_MSG" {
+			continue
+		}
+		if prefix != "" && (!strings.HasPrefix(path, prefix) || strings.Contains(path[len(prefix):], "/")) {
+			continue
+		}
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	return paths, nil
+}
+
+// ListDir ignores ref in favour of the configured change/revision, and
+// filters the revision's changed files down to the ones directly inside dir.
+//
+// Known limitation: unlike the git/github backends, this can only see
+// files the current revision actually touched. Gerrit's REST API has no
+// endpoint for a branch-wide file tree listing - the only branch-scoped
+// file endpoint it documents fetches one named file's content, not a
+// directory - so there's no way to see a namespace's locals/variables file
+// that the change didn't modify. A default_tags expression referencing
+// such a file will fail to resolve here even though it would under
+// -vcs=git or -vcs=github.
+func (g *gerritVCS) ListDir(_, dir string) ([]string, error) {
+	return g.listFiles(strings.TrimRight(dir, "/") + "/")
+}
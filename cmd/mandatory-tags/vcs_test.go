@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestGitVCSListDir(t *testing.T) {
+	dir := t.TempDir()
+	runGit := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+		)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v - %s", args, err, out)
+		}
+	}
+
+	runGit("init", "-q", "-b", "main")
+	if err := os.MkdirAll(filepath.Join(dir, "namespaces", "test-namespace", "resources"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	mainTf := filepath.Join(dir, "namespaces", "test-namespace", "resources", "main.tf")
+	if err := os.WriteFile(mainTf, []byte(`provider "aws" {}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runGit("add", "-A")
+	runGit("commit", "-q", "-m", "initial")
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	paths, err := gitVCS{}.ListDir("main", "namespaces/test-namespace/resources")
+	if err != nil {
+		t.Fatalf("ListDir returned an error: %v", err)
+	}
+	want := []string{"namespaces/test-namespace/resources/main.tf"}
+	if fmt.Sprint(paths) != fmt.Sprint(want) {
+		t.Fatalf("ListDir = %v, want %v", paths, want)
+	}
+}
+
+func TestGitHubVCSListDir(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/example/repo/contents/namespaces/test-namespace/resources" {
+			t.Errorf("unexpected request path: %s", r.URL.Path)
+		}
+		if got := r.URL.Query().Get("ref"); got != "feature-branch" {
+			t.Errorf("ref query param = %q, want %q", got, "feature-branch")
+		}
+		fmt.Fprint(w, `[
+			{"path": "namespaces/test-namespace/resources/main.tf", "type": "file"},
+			{"path": "namespaces/test-namespace/resources/locals.tf", "type": "file"},
+			{"path": "namespaces/test-namespace/resources/subdir", "type": "dir"}
+		]`)
+	}))
+	defer server.Close()
+
+	g := &githubVCS{token: "fake-token", repo: "example/repo", client: server.Client(), baseURL: server.URL}
+
+	paths, err := g.ListDir("feature-branch", "namespaces/test-namespace/resources")
+	if err != nil {
+		t.Fatalf("ListDir returned an error: %v", err)
+	}
+
+	sort.Strings(paths)
+	want := []string{"namespaces/test-namespace/resources/locals.tf", "namespaces/test-namespace/resources/main.tf"}
+	if fmt.Sprint(paths) != fmt.Sprint(want) {
+		t.Fatalf("ListDir = %v, want %v (the \"dir\" entry should have been filtered out)", paths, want)
+	}
+}
+
+func TestGerritVCSListDirOnlySeesRevisionFiles(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		want := "/a/changes/12345/revisions/current/files"
+		if r.URL.Path != want {
+			t.Errorf("unexpected request path: %s, want %s", r.URL.Path, want)
+		}
+		fmt.Fprint(w, ")]}'\n{"+
+			`"/COMMIT_MSG": {},`+
+			`"namespaces/test-namespace/resources/main.tf": {},`+
+			`"namespaces/other-namespace/resources/main.tf": {}`+
+			"}")
+	}))
+	defer server.Close()
+
+	g := &gerritVCS{host: server.URL, change: "12345", revision: "current", client: server.Client()}
+
+	// ListDir is documented to only see files the change's current
+	// revision touched - it must not reach for a branch-wide tree listing
+	// endpoint, since Gerrit's REST API doesn't expose one.
+	paths, err := g.ListDir("main", "namespaces/test-namespace/resources")
+	if err != nil {
+		t.Fatalf("ListDir returned an error: %v", err)
+	}
+
+	want := []string{"namespaces/test-namespace/resources/main.tf"}
+	if fmt.Sprint(paths) != fmt.Sprint(want) {
+		t.Fatalf("ListDir = %v, want %v", paths, want)
+	}
+}